@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// NewSSHAuthFromMemory builds an SSH transport.AuthMethod entirely from
+// in-memory byte buffers, rather than requiring a private key and
+// known_hosts file on disk. This matters when the mirror runs in an
+// ephemeral CI container or Kubernetes job, where the credentials live in
+// environment variables or a secret manager and writing them to ~/.ssh is
+// undesirable.
+//
+// knownHostsPEM, despite the name, holds plain known_hosts data (one entry
+// per line) rather than a PEM block; the name matches privateKeyPEM and
+// passphrase for symmetry since all three are opaque byte buffers sourced
+// the same way. When knownHostsPEM is empty, host key verification falls
+// back to gossh.InsecureIgnoreHostKey only if insecureIgnoreHostKey is
+// true; otherwise an empty knownHostsPEM is an error, since silently
+// skipping host key verification is not something this helper will do
+// implicitly.
+func NewSSHAuthFromMemory(user string, privateKeyPEM, passphrase, knownHostsPEM []byte, insecureIgnoreHostKey bool) (transport.AuthMethod, error) {
+	auth, err := ssh.NewPublicKeys(user, privateKeyPEM, string(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse in-memory private key: %w", err)
+	}
+
+	switch {
+	case len(knownHostsPEM) == 0 && insecureIgnoreHostKey:
+		auth.HostKeyCallback = gossh.InsecureIgnoreHostKey()
+	case len(knownHostsPEM) == 0:
+		return nil, fmt.Errorf("no known_hosts data given and insecureIgnoreHostKey is false")
+	default:
+		callback, err := knownHostsCallbackFromMemory(knownHostsPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse in-memory known_hosts: %w", err)
+		}
+		auth.HostKeyCallback = callback
+	}
+
+	return auth, nil
+}
+
+// knownHostsCallbackFromMemory builds a host key callback from raw
+// known_hosts data without requiring it to live at a fixed path on disk.
+// ssh.NewKnownHostsCallback only accepts file paths, so the data is
+// spooled to a short-lived temporary file that is removed before this
+// function returns.
+func knownHostsCallbackFromMemory(knownHostsPEM []byte) (gossh.HostKeyCallback, error) {
+	f, err := ioutil.TempFile("", "git-mirror-me-known-hosts-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a temporary known_hosts file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.Write(knownHostsPEM); err != nil {
+		return nil, fmt.Errorf("failed to write a temporary known_hosts file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close a temporary known_hosts file: %w", err)
+	}
+
+	return ssh.NewKnownHostsCallback(f.Name())
+}
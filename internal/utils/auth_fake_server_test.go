@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// execCommandRE extracts the path argument out of an "exec" request
+// payload of the form git-upload-pack '<path>', matching how go-git's ssh
+// transport (and openssh itself) issue the command.
+var execCommandRE = regexp.MustCompile(`^git-upload-pack\s+'?([^']+?)'?\s*$`)
+
+// fakeSSHServer is a minimal, in-process SSH server authenticating a
+// single public key, and serving exactly one "git-upload-pack" exec
+// request per session by handing the channel to the real git-upload-pack
+// binary. It exists so NewSSHAuthFromMemory can be driven through an
+// actual SSH handshake and a real git clone, rather than only unit-tested
+// in isolation.
+type fakeSSHServer struct {
+	listener net.Listener
+	addr     string
+}
+
+func newFakeSSHServer(t *testing.T, hostKey gossh.Signer, authorizedKey gossh.PublicKey) *fakeSSHServer {
+	t.Helper()
+
+	config := &gossh.ServerConfig{
+		PublicKeyCallback: func(conn gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return &gossh.Permissions{}, nil
+			}
+			return nil, fmt.Errorf("unauthorized key for user %s", conn.User())
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen for the fake SSH server: %s", err)
+	}
+
+	s := &fakeSSHServer{listener: listener, addr: listener.Addr().String()}
+	go s.serve(t, config)
+	return s
+}
+
+func (s *fakeSSHServer) serve(t *testing.T, config *gossh.ServerConfig) {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	sshConn, chans, reqs, err := gossh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer sshConn.Close()
+	go gossh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(gossh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.serveSession(channel, requests)
+	}
+}
+
+func (s *fakeSSHServer) serveSession(channel gossh.Channel, requests <-chan *gossh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+
+		var execMsg struct{ Command string }
+		gossh.Unmarshal(req.Payload, &execMsg)
+		req.Reply(true, nil)
+
+		m := execCommandRE.FindStringSubmatch(execMsg.Command)
+		if m == nil {
+			channel.Stderr().Write([]byte("unsupported command: " + execMsg.Command))
+			return
+		}
+
+		cmd := exec.Command("git-upload-pack", m[1])
+		cmd.Stdin = channel
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+		cmd.Run()
+		return
+	}
+}
+
+// TestNewSSHAuthFromMemoryOverFakeServer drives an auth method built by
+// NewSSHAuthFromMemory through a real SSH handshake against an in-process
+// server, and clones a repository created with NewTestRepo over it into a
+// bare mirror via NewBareRepoFromRemote, end to end.
+func TestNewSSHAuthFromMemoryOverFakeServer(t *testing.T) {
+	if _, err := exec.LookPath("git-upload-pack"); err != nil {
+		t.Skip("git-upload-pack binary not available to back the fake SSH server")
+	}
+
+	hostPub, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a host key: %s", err)
+	}
+	hostSigner, err := gossh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatalf("failed to build a host signer: %s", err)
+	}
+	hostSSHPub, err := gossh.NewPublicKey(hostPub)
+	if err != nil {
+		t.Fatalf("failed to derive the host public key: %s", err)
+	}
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a client key: %s", err)
+	}
+	clientSSHPub, err := gossh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatalf("failed to derive the client public key: %s", err)
+	}
+	block, err := gossh.MarshalPrivateKey(clientPriv, "git-mirror-me-test")
+	if err != nil {
+		t.Fatalf("failed to marshal the client private key: %s", err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(block)
+
+	server := newFakeSSHServer(t, hostSigner, clientSSHPub)
+	_, port, err := net.SplitHostPort(server.addr)
+	if err != nil {
+		t.Fatalf("failed to split the fake server address: %s", err)
+	}
+
+	knownHostsLine := bytes.TrimRight(gossh.MarshalAuthorizedKey(hostSSHPub), "\n")
+	knownHostsPEM := append([]byte(fmt.Sprintf("[127.0.0.1]:%s ", port)), knownHostsLine...)
+
+	auth, err := NewSSHAuthFromMemory("git", privateKeyPEM, nil, knownHostsPEM, false)
+	if err != nil {
+		t.Fatalf("failed to build an auth method from memory: %s", err)
+	}
+
+	srcPath, err := ioutil.TempDir("/tmp", "git-mirror-me-test-src-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(srcPath)
+	if _, _, err := NewTestRepo(srcPath, []string{"refs/heads/foo"}); err != nil {
+		t.Fatalf("failed to create the source test repo: %s", err)
+	}
+
+	dstParent, err := ioutil.TempDir("/tmp", "git-mirror-me-test-dst-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dstParent)
+	dstPath := dstParent + "/dst.git"
+
+	// NewBareRepoFromRemote (a bare, mirror-mode git.PlainClone) is called
+	// with FilterNone, so it skips the filter capability probe and the fake
+	// server only has to serve the one SSH session of the clone itself.
+	url := fmt.Sprintf("ssh://git@127.0.0.1:%s%s", port, srcPath)
+	progress := &recordingProgress{}
+	repo, _, err := NewBareRepoFromRemote(dstPath, url, auth, FilterNone, false, progress)
+	if err != nil {
+		t.Fatalf("failed to clone %s over the fake SSH server: %s", url, err)
+	}
+
+	refs, err := RepoRefsSlice(repo)
+	if err != nil {
+		t.Fatalf("failed to get repo's refs: %s", err)
+	}
+	if !SlicesAreEqual(refs, []string{"HEAD", "refs/heads/master", "refs/heads/foo"}) {
+		t.Fatalf("unexpected refs in the cloned repo: %s", refs)
+	}
+
+	// git-upload-pack is not guaranteed to emit progress text for such a
+	// tiny repo, so this only logs rather than failing the test; the point
+	// is exercising that NewBareRepoFromRemote actually wires progress
+	// through to go-git's CloneOptions rather than asserting a specific
+	// phase sequence.
+	t.Logf("recorded phases: %v", progress.phases)
+}
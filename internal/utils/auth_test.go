@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// TestNewSSHAuthFromMemory tests the NewSSHAuthFromMemory function.
+func TestNewSSHAuthFromMemory(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate an ed25519 key: %s", err)
+	}
+	block, err := gossh.MarshalPrivateKey(priv, "git-mirror-me-test")
+	if err != nil {
+		t.Fatalf("failed to marshal the private key: %s", err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(block)
+
+	sshPub, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to derive the public key: %s", err)
+	}
+	knownHostsLine := bytes.TrimRight(gossh.MarshalAuthorizedKey(sshPub), "\n")
+	knownHostsPEM := append([]byte("example.org "), knownHostsLine...)
+
+	{
+		auth, err := NewSSHAuthFromMemory("git", privateKeyPEM, nil, knownHostsPEM, false)
+		if err != nil {
+			t.Fatalf("failed to build an auth method from memory: %s", err)
+		}
+		if auth.Name() != "ssh-public-keys" {
+			t.Fatalf("unexpected auth method name: %s", auth.Name())
+		}
+	}
+	{
+		if _, err := NewSSHAuthFromMemory("git", privateKeyPEM, nil, nil, false); err == nil {
+			t.Fatal("expected an error when known_hosts is empty and insecureIgnoreHostKey is false")
+		}
+	}
+	{
+		auth, err := NewSSHAuthFromMemory("git", privateKeyPEM, nil, nil, true)
+		if err != nil {
+			t.Fatalf("failed to build an auth method with the insecure fallback: %s", err)
+		}
+		if auth == nil {
+			t.Fatal("expected a non-nil auth method")
+		}
+	}
+	{
+		if _, err := NewSSHAuthFromMemory("git", []byte("not a key"), nil, knownHostsPEM, false); err == nil {
+			t.Fatal("expected an error for a malformed private key")
+		}
+	}
+}
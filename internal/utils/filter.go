@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+)
+
+// FilterSpec is a partial clone filter expressed in the same syntax git
+// itself accepts on the command line (e.g. "blob:none", "blob:limit=1m",
+// "tree:0"). It is serialized verbatim into the packp filter-spec
+// extension sent to filter-capable servers.
+type FilterSpec string
+
+const (
+	// FilterNone requests no filter; every object reachable from the
+	// wanted refs is fetched, as before filters existed.
+	FilterNone FilterSpec = ""
+	// FilterBlobNone excludes all blobs from the initial fetch; they are
+	// fetched lazily, on demand, from a promisor remote.
+	FilterBlobNone FilterSpec = "blob:none"
+	// FilterTreeRoot excludes every tree and blob below the root tree.
+	FilterTreeRoot FilterSpec = "tree:0"
+)
+
+// FilterBlobLimit builds a FilterSpec excluding blobs larger than size,
+// using git's own size suffixes (e.g. "1k", "1m", "1g").
+func FilterBlobLimit(size string) FilterSpec {
+	return FilterSpec("blob:limit=" + size)
+}
+
+// NewBareRepoFromRemote clones url into a new bare repository at path,
+// mirroring its branches and tags directly onto refs/heads/* and
+// refs/tags/* (via CloneOptions.Mirror) rather than leaving them nested
+// under refs/remotes/origin/* the way a non-mirror bare clone would.
+//
+// filter only ever goes as far as this go-git version allows: it is
+// always used to probe whether the remote advertises the "filter"
+// capability (returning an error if it doesn't and allowFullClone is
+// false), but it can never actually be sent to the server, since
+// CloneOptions has no field for it. So whenever filter is not FilterNone,
+// the clone ends up fetching every object — a full clone — and the
+// returned negotiated is false even though repo itself is valid and
+// usable, so callers can tell the two apart without inspecting an error.
+//
+// progress may be nil, in which case the clone reports nothing; otherwise
+// the raw sideband text go-git's clone writes is parsed into structured
+// calls against it via NewProgressWriter.
+func NewBareRepoFromRemote(path, url string, auth transport.AuthMethod, filter FilterSpec, allowFullClone bool, progress Progress) (repo *git.Repository, negotiated bool, err error) {
+	opts := &git.CloneOptions{
+		URL:    url,
+		Auth:   auth,
+		Mirror: true,
+	}
+	if progress != nil {
+		opts.Progress = NewProgressWriter(progress)
+	}
+
+	filterRequested := filter != FilterNone
+	if filterRequested {
+		supported, err := remoteSupportsFilter(url, auth)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to probe %s for the filter capability: %w", url, err)
+		}
+		if !supported && !allowFullClone {
+			return nil, false, fmt.Errorf("remote %s does not advertise the filter capability and allowFullClone is false", url)
+		}
+	}
+
+	repo, err = git.PlainClone(path, true, opts)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to clone %s into %s: %w", url, path, err)
+	}
+
+	return repo, !filterRequested, nil
+}
+
+// remoteSupportsFilter reports whether url advertises the "filter"
+// capability on its upload-pack service.
+func remoteSupportsFilter(url string, auth transport.AuthMethod) (bool, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse endpoint %s: %w", url, err)
+	}
+	cli, err := client.NewClient(ep)
+	if err != nil {
+		return false, fmt.Errorf("failed to get a transport client for %s: %w", url, err)
+	}
+	session, err := cli.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return false, fmt.Errorf("failed to open an upload-pack session against %s: %w", url, err)
+	}
+	defer session.Close()
+	info, err := session.AdvertisedReferences()
+	if err != nil {
+		return false, fmt.Errorf("failed to get advertised refs from %s: %w", url, err)
+	}
+	return info.Capabilities.Supports(capability.Filter), nil
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestFilterBlobLimit tests the FilterBlobLimit function.
+func TestFilterBlobLimit(t *testing.T) {
+	if FilterBlobLimit("1m") != FilterSpec("blob:limit=1m") {
+		t.Fatalf("unexpected filter spec: %s", FilterBlobLimit("1m"))
+	}
+}
+
+// TestNewBareRepoFromRemoteFilterNotNegotiated tests that requesting a
+// filter still produces a usable mirror clone with every ref present, but
+// reports negotiated as false rather than pretending the filter was
+// actually honored (see NewBareRepoFromRemote's doc comment for why:
+// go-git's CloneOptions has no field to send one). It relies on a local
+// git binary to serve the source repo over the local transport, since
+// go-git itself doesn't implement a server for it.
+func TestNewBareRepoFromRemoteFilterNotNegotiated(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available for a local transport clone")
+	}
+
+	srcPath, err := ioutil.TempDir("/tmp", "git-mirror-me-test-src-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(srcPath)
+	if _, _, err := NewTestRepo(srcPath, []string{"refs/heads/foo"}); err != nil {
+		t.Fatalf("failed to create the source test repo: %s", err)
+	}
+
+	dstPath, err := ioutil.TempDir("/tmp", "git-mirror-me-test-dst-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(dstPath)
+
+	repo, negotiated, err := NewBareRepoFromRemote(dstPath, srcPath, nil, FilterBlobNone, true, nil)
+	if err != nil {
+		t.Fatalf("expected a usable repository even though the filter could not be negotiated, got: %s", err)
+	}
+	if negotiated {
+		t.Fatal("expected negotiated to be false: this go-git version cannot send a filter-spec")
+	}
+
+	refs, err := RepoRefsSlice(repo)
+	if err != nil {
+		t.Fatalf("failed to get repo's refs: %s", err)
+	}
+	if !SlicesAreEqual(refs, []string{"HEAD", "refs/heads/master", "refs/heads/foo"}) {
+		t.Fatalf("unexpected refs in the cloned repo: %s", refs)
+	}
+}
+
+// TestNewBareRepoFromRemoteFilterDenied tests that a requested filter fails
+// outright when the remote does not advertise the filter capability and
+// allowFullClone is false, instead of silently falling back to a full clone.
+func TestNewBareRepoFromRemoteFilterDenied(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available for a local transport clone")
+	}
+
+	srcPath, err := ioutil.TempDir("/tmp", "git-mirror-me-test-src-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(srcPath)
+	if _, _, err := NewTestRepo(srcPath, []string{"refs/heads/foo"}); err != nil {
+		t.Fatalf("failed to create the source test repo: %s", err)
+	}
+
+	dstPath, err := ioutil.TempDir("/tmp", "git-mirror-me-test-dst-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(dstPath)
+
+	if _, _, err := NewBareRepoFromRemote(dstPath, srcPath, nil, FilterBlobNone, false, nil); err == nil {
+		t.Fatal("expected an error when the filter can't be negotiated and allowFullClone is false")
+	}
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// NewNamespacedBareRepo creates a new, empty bare repository at path meant
+// to be shared by several source repositories, each kept apart from the
+// others under its own refs/namespaces/<sourceID>/ prefix. This is the
+// git-namespaces pattern applied to mirroring: one backup repo can
+// consolidate many upstreams while still serving each namespace as if it
+// were a standalone repo, e.g. over git:// with GIT_NAMESPACE set.
+func NewNamespacedBareRepo(path string) (*git.Repository, error) {
+	return NewBareRepo(path)
+}
+
+// NamespacePrefix returns the refs/namespaces/<sourceID>/ prefix that
+// sourceID's refs are rewritten under.
+func NamespacePrefix(sourceID string) string {
+	return "refs/namespaces/" + sourceID + "/"
+}
+
+// FetchRefSpecsForNamespace builds the refspecs used to fetch sourceID's
+// branches, tags and HEAD directly into its namespace, so that a
+// concurrently running mirror for a different source can never observe or
+// clobber them.
+func FetchRefSpecsForNamespace(sourceID string) []config.RefSpec {
+	prefix := NamespacePrefix(sourceID)
+	return []config.RefSpec{
+		config.RefSpec(fmt.Sprintf("+refs/heads/*:%srefs/heads/*", prefix)),
+		config.RefSpec(fmt.Sprintf("+refs/tags/*:%srefs/tags/*", prefix)),
+		config.RefSpec(fmt.Sprintf("+HEAD:%sHEAD", prefix)),
+	}
+}
+
+// PruneNamespace removes every ref under sourceID's namespace whose
+// namespace-relative name (e.g. "refs/heads/stale") is not in keep. It
+// never touches a ref outside that namespace, so a mirror pruning one
+// source's deleted branches can run concurrently with a mirror for a
+// different source without clobbering its refs.
+func PruneNamespace(repo *git.Repository, sourceID string, keep []string) error {
+	prefix := NamespacePrefix(sourceID)
+	keepSet := make(map[string]bool, len(keep))
+	for _, ref := range keep {
+		keepSet[ref] = true
+	}
+
+	iter, err := repo.References()
+	if err != nil {
+		return fmt.Errorf("failed to get refs: %w", err)
+	}
+	var stale []plumbing.ReferenceName
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		if keepSet[strings.TrimPrefix(name, prefix)] {
+			return nil
+		}
+		stale = append(stale, ref.Name())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate refs: %w", err)
+	}
+
+	for _, name := range stale {
+		if err := repo.Storer.RemoveReference(name); err != nil {
+			return fmt.Errorf("failed to remove stale ref %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RepoRefsSliceNamespaced is RepoRefsSlice restricted to the refs living
+// under sourceID's namespace, with the namespace prefix stripped so the
+// result reads the same way RepoRefsSlice would for a standalone clone of
+// that source.
+func RepoRefsSliceNamespaced(repo *git.Repository, sourceID string) ([]string, error) {
+	all, err := RepoRefsSlice(repo)
+	if err != nil {
+		return nil, err
+	}
+	prefix := NamespacePrefix(sourceID)
+	refs := []string{}
+	for _, ref := range all {
+		if !strings.HasPrefix(ref, prefix) {
+			continue
+		}
+		refs = append(refs, strings.TrimPrefix(ref, prefix))
+	}
+	return refs, nil
+}
+
+// RepoRefsCheckHashNamespaced is RepoRefsCheckHash restricted to the hash
+// references living under sourceID's namespace. Unlike RepoRefsCheckHash,
+// it reports false when the namespace holds no hash references at all.
+func RepoRefsCheckHashNamespaced(repo *git.Repository, sourceID string, hash plumbing.Hash) (bool, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return false, fmt.Errorf("failed to get refs: %w", err)
+	}
+	prefix := NamespacePrefix(sourceID)
+	ok := true
+	found := false
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if !strings.HasPrefix(ref.Name().String(), prefix) {
+			return nil
+		}
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		found = true
+		if ref.Hash() != hash {
+			ok = false
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to iterate refs: %w", err)
+	}
+	return ok && found, nil
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TestNamespacePrefix tests the NamespacePrefix function.
+func TestNamespacePrefix(t *testing.T) {
+	if NamespacePrefix("source-a") != "refs/namespaces/source-a/" {
+		t.Fatalf("unexpected namespace prefix: %s", NamespacePrefix("source-a"))
+	}
+}
+
+// TestRepoRefsNamespaced tests RepoRefsSliceNamespaced and
+// RepoRefsCheckHashNamespaced together, since both need the same two
+// namespaces seeded with independent refs to be meaningful.
+func TestRepoRefsNamespaced(t *testing.T) {
+	path, err := ioutil.TempDir("/tmp", "git-mirror-me-test-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(path)
+	repo, err := NewNamespacedBareRepo(path)
+	if err != nil {
+		t.Fatalf("failed to create a namespaced bare repo: %s", err)
+	}
+
+	hashA := plumbing.NewHash("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	hashB := plumbing.NewHash("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	for _, ref := range []string{
+		NamespacePrefix("source-a") + "refs/heads/master",
+		NamespacePrefix("source-a") + "refs/heads/feature",
+	} {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(ref), hashA)); err != nil {
+			t.Fatalf("failed to set ref %s: %s", ref, err)
+		}
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(
+		plumbing.ReferenceName(NamespacePrefix("source-b")+"refs/heads/master"), hashB)); err != nil {
+		t.Fatalf("failed to set source-b ref: %s", err)
+	}
+
+	refsA, err := RepoRefsSliceNamespaced(repo, "source-a")
+	if err != nil {
+		t.Fatalf("failed to get source-a refs: %s", err)
+	}
+	if !SlicesAreEqual(refsA, []string{"refs/heads/master", "refs/heads/feature"}) {
+		t.Fatalf("unexpected source-a refs: %s", refsA)
+	}
+
+	refsB, err := RepoRefsSliceNamespaced(repo, "source-b")
+	if err != nil {
+		t.Fatalf("failed to get source-b refs: %s", err)
+	}
+	if !SlicesAreEqual(refsB, []string{"refs/heads/master"}) {
+		t.Fatalf("unexpected source-b refs: %s", refsB)
+	}
+
+	ok, err := RepoRefsCheckHashNamespaced(repo, "source-a", hashA)
+	if err != nil {
+		t.Fatalf("RepoRefsCheckHashNamespaced failed: %s", err)
+	}
+	if !ok {
+		t.Fatal("expected source-a refs to all match hashA")
+	}
+
+	ok, err = RepoRefsCheckHashNamespaced(repo, "source-a", hashB)
+	if err != nil {
+		t.Fatalf("RepoRefsCheckHashNamespaced failed: %s", err)
+	}
+	if ok {
+		t.Fatal("expected source-a refs not to match hashB")
+	}
+}
+
+// TestFetchRefSpecsForNamespace tests the FetchRefSpecsForNamespace function.
+func TestFetchRefSpecsForNamespace(t *testing.T) {
+	specs := SpecsToStrings(FetchRefSpecsForNamespace("source-a"))
+	if !SlicesAreEqual(specs, []string{
+		"+refs/heads/*:refs/namespaces/source-a/refs/heads/*",
+		"+refs/tags/*:refs/namespaces/source-a/refs/tags/*",
+		"+HEAD:refs/namespaces/source-a/HEAD",
+	}) {
+		t.Fatalf("unexpected refspecs: %s", specs)
+	}
+}
+
+// TestPruneNamespace tests that PruneNamespace removes only the stale refs
+// under the given namespace, leaving both the refs it was told to keep and
+// every other namespace's refs untouched.
+func TestPruneNamespace(t *testing.T) {
+	path, err := ioutil.TempDir("/tmp", "git-mirror-me-test-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(path)
+	repo, err := NewNamespacedBareRepo(path)
+	if err != nil {
+		t.Fatalf("failed to create a namespaced bare repo: %s", err)
+	}
+
+	hash := plumbing.NewHash("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	for _, ref := range []string{
+		NamespacePrefix("source-a") + "refs/heads/master",
+		NamespacePrefix("source-a") + "refs/heads/stale",
+		NamespacePrefix("source-b") + "refs/heads/master",
+	} {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(ref), hash)); err != nil {
+			t.Fatalf("failed to set ref %s: %s", ref, err)
+		}
+	}
+
+	if err := PruneNamespace(repo, "source-a", []string{"refs/heads/master"}); err != nil {
+		t.Fatalf("failed to prune source-a: %s", err)
+	}
+
+	refsA, err := RepoRefsSliceNamespaced(repo, "source-a")
+	if err != nil {
+		t.Fatalf("failed to get source-a refs: %s", err)
+	}
+	if !SlicesAreEqual(refsA, []string{"refs/heads/master"}) {
+		t.Fatalf("expected source-a's stale ref to be pruned, got: %s", refsA)
+	}
+
+	refsB, err := RepoRefsSliceNamespaced(repo, "source-b")
+	if err != nil {
+		t.Fatalf("failed to get source-b refs: %s", err)
+	}
+	if !SlicesAreEqual(refsB, []string{"refs/heads/master"}) {
+		t.Fatalf("expected source-b's refs to be untouched, got: %s", refsB)
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TestNewTestRepoSHA256 tests requesting a sha256 repo. go-git only
+// compiles in sha256 support when built with its "sha256" build tag,
+// which this module does not (yet) set anywhere; in that ordinary build,
+// requesting ObjectFormatSHA256 must fail loudly rather than silently
+// hand back a truncated sha1 repository. Building and running this test
+// with `go test -tags sha256 ./...` instead exercises the full
+// round-trip in the branch below.
+func TestNewTestRepoSHA256(t *testing.T) {
+	path, err := ioutil.TempDir("/tmp", "git-mirror-me-test-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary repo: %s", err)
+	}
+	defer os.RemoveAll(path)
+
+	repo, hash, err := NewTestRepo(path, []string{"refs/heads/foo"}, WithObjectFormat(ObjectFormatSHA256))
+
+	if objectHashSize != 32 {
+		if err == nil {
+			t.Fatal("expected an error requesting sha256 without go-git's sha256 build tag")
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("failed to create a sha256 test repo: %s", err)
+	}
+	if len(hash.String()) != 64 {
+		t.Fatalf("expected a 64-character sha256 hash, got %q", hash.String())
+	}
+
+	refs, err := RepoRefsSlice(repo)
+	if err != nil {
+		t.Fatalf("failed to get repo's refs: %s", err)
+	}
+	if !SlicesAreEqual(refs, []string{"HEAD", "refs/heads/master", "refs/heads/foo"}) {
+		t.Fatalf("unexpected refs in repo: %s", refs)
+	}
+
+	ok, err := RepoRefsCheckHash(repo, hash)
+	if err != nil {
+		t.Fatalf("RepoRefsCheckHash failed: %s", err)
+	}
+	if !ok {
+		t.Fatal("unexpected hash check result")
+	}
+}
+
+// TestParseHash tests the ParseHash function.
+func TestParseHash(t *testing.T) {
+	{
+		h, err := ParseHash("")
+		if err != nil {
+			t.Fatalf("unexpected error for an empty hash: %s", err)
+		}
+		if h != plumbing.ZeroHash {
+			t.Fatalf("expected the zero hash, got %s", h)
+		}
+	}
+	{
+		sha1 := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		h, err := ParseHash(sha1)
+		if err != nil {
+			t.Fatalf("unexpected error for a sha1 hash: %s", err)
+		}
+		if h.String() != sha1 {
+			t.Fatalf("unexpected round-trip: %s", h)
+		}
+	}
+	{
+		if _, err := ParseHash("too-short"); err == nil {
+			t.Fatal("expected an error for a malformed hash")
+		}
+	}
+	{
+		sha256 := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		h, err := ParseHash(sha256)
+		if objectHashSize != 32 {
+			if err == nil {
+				t.Fatal("expected an error for a sha256 hash without go-git's sha256 build tag")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for a sha256 hash: %s", err)
+		}
+		if h.String() != sha256 {
+			t.Fatalf("unexpected round-trip: %s", h)
+		}
+	}
+}
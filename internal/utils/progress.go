@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Progress receives incremental updates about an in-flight clone, fetch or
+// push. Implementations must be safe to call from whichever goroutine is
+// driving go-git's sideband demultiplexer.
+type Progress interface {
+	// OnPhase is called whenever the operation enters a new named phase,
+	// e.g. "Compressing objects" or "Receiving objects".
+	OnPhase(name string)
+	// OnObjects reports the running count of objects processed against
+	// the total advertised by the remote, when known.
+	OnObjects(received, total uint64)
+	// OnBytes reports n additional bytes transferred since the previous
+	// call, not a running total, so implementations can accumulate it
+	// directly (e.g. into a Prometheus counter).
+	OnBytes(n uint64)
+	// OnRef is called once per ref that was created, fast-forwarded, or
+	// force-updated by the operation.
+	OnRef(ref string, oldHash, newHash plumbing.Hash)
+}
+
+// objectsLineRE matches the "<phase>: NN% (x/y), <size> <unit>" lines
+// go-git's sideband demultiplexer writes to CloneOptions/FetchOptions/
+// PushOptions' Progress writer, mirroring git's own porcelain progress
+// output (e.g. "Receiving objects: 100% (4/4), 1.21 KiB | 620.00 KiB/s,
+// done."). The size group is optional: "Counting objects" and
+// "Compressing objects" lines never carry one.
+var objectsLineRE = regexp.MustCompile(`^([\w ]+):\s+\d+%\s+\((\d+)/(\d+)\)(?:,\s*([\d.]+)\s*(bytes|B|KiB|MiB|GiB))?`)
+
+// objectsOnlyPhases are phases git reports progress for that count objects
+// being examined or packed, not objects actually transferred to the
+// client; OnObjects is reserved for the latter ("Receiving objects",
+// "Resolving deltas"), so these are reported via OnPhase alone.
+var objectsOnlyPhases = map[string]bool{
+	"Counting objects":    true,
+	"Compressing objects": true,
+}
+
+// NewProgressWriter adapts p into the io.Writer expected by go-git's
+// CloneOptions, FetchOptions and PushOptions Progress field, so the raw
+// sideband text the server sends ends up as structured calls against p.
+func NewProgressWriter(p Progress) io.Writer {
+	return &progressWriter{p: p}
+}
+
+type progressWriter struct {
+	p         Progress
+	lastBytes uint64
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	for _, line := range strings.FieldsFunc(string(b), func(r rune) bool { return r == '\r' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		w.parseLine(line)
+	}
+	return len(b), nil
+}
+
+func (w *progressWriter) parseLine(line string) {
+	m := objectsLineRE.FindStringSubmatch(line)
+	if m == nil {
+		w.p.OnPhase(line)
+		return
+	}
+
+	phase := strings.TrimSpace(m[1])
+	w.p.OnPhase(phase)
+
+	if !objectsOnlyPhases[phase] {
+		received, _ := strconv.ParseUint(m[2], 10, 64)
+		total, _ := strconv.ParseUint(m[3], 10, 64)
+		w.p.OnObjects(received, total)
+	}
+
+	if m[4] == "" {
+		return
+	}
+	value, err := strconv.ParseFloat(m[4], 64)
+	if err != nil {
+		return
+	}
+	cumulative := sizeToBytes(value, m[5])
+	if cumulative > w.lastBytes {
+		w.p.OnBytes(cumulative - w.lastBytes)
+		w.lastBytes = cumulative
+	}
+}
+
+// sizeToBytes converts a size reported with git's own progress units
+// (B, KiB, MiB, GiB) into a byte count.
+func sizeToBytes(value float64, unit string) uint64 {
+	switch unit {
+	case "KiB":
+		return uint64(value * 1024)
+	case "MiB":
+		return uint64(value * 1024 * 1024)
+	case "GiB":
+		return uint64(value * 1024 * 1024 * 1024)
+	default:
+		return uint64(value)
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// PrometheusProgress is a Progress that accumulates counters suitable for
+// exposing in the Prometheus text exposition format, for mirrors that run
+// as a long-lived sidecar and get scraped rather than read by a human.
+type PrometheusProgress struct {
+	objectsTotal      uint64
+	bytesTotal        uint64
+	lastSuccessUnixTS int64
+
+	mu       sync.Mutex
+	refTotal map[string]uint64
+}
+
+// NewPrometheusProgress returns a ready to use PrometheusProgress.
+func NewPrometheusProgress() *PrometheusProgress {
+	return &PrometheusProgress{refTotal: map[string]uint64{}}
+}
+
+// OnPhase implements Progress. Phase changes aren't a metric on their own;
+// PrometheusProgress only tracks the counters named in WriteTo.
+func (p *PrometheusProgress) OnPhase(name string) {}
+
+// OnObjects implements Progress.
+func (p *PrometheusProgress) OnObjects(received, total uint64) {
+	atomic.StoreUint64(&p.objectsTotal, received)
+}
+
+// OnBytes implements Progress.
+func (p *PrometheusProgress) OnBytes(n uint64) {
+	atomic.AddUint64(&p.bytesTotal, n)
+}
+
+// OnRef implements Progress.
+func (p *PrometheusProgress) OnRef(ref string, oldHash, newHash plumbing.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refTotal[ref]++
+}
+
+// MarkSuccess records unixTS as the time a mirror pass last completed
+// successfully; callers pass time.Now().Unix().
+func (p *PrometheusProgress) MarkSuccess(unixTS int64) {
+	atomic.StoreInt64(&p.lastSuccessUnixTS, unixTS)
+}
+
+// WriteTo renders the accumulated counters in the Prometheus text
+// exposition format.
+func (p *PrometheusProgress) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var n int64
+	write := func(format string, args ...interface{}) {
+		written, _ := fmt.Fprintf(w, format, args...)
+		n += int64(written)
+	}
+
+	write("# HELP git_mirror_objects_total objects processed by the most recent operation.\n")
+	write("# TYPE git_mirror_objects_total gauge\n")
+	write("git_mirror_objects_total %d\n", atomic.LoadUint64(&p.objectsTotal))
+
+	write("# HELP git_mirror_bytes_total bytes transferred across all operations.\n")
+	write("# TYPE git_mirror_bytes_total counter\n")
+	write("git_mirror_bytes_total %d\n", atomic.LoadUint64(&p.bytesTotal))
+
+	write("# HELP git_mirror_last_success_timestamp_seconds unix time of the last successful mirror pass.\n")
+	write("# TYPE git_mirror_last_success_timestamp_seconds gauge\n")
+	write("git_mirror_last_success_timestamp_seconds %d\n", atomic.LoadInt64(&p.lastSuccessUnixTS))
+
+	write("# HELP git_mirror_ref_updates_total ref updates observed, labeled by ref.\n")
+	write("# TYPE git_mirror_ref_updates_total counter\n")
+	for _, ref := range SortSlice(refTotalKeys(p.refTotal)) {
+		write("git_mirror_ref_updates_total{ref=%q} %d\n", ref, p.refTotal[ref])
+	}
+
+	return n, nil
+}
+
+func refTotalKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
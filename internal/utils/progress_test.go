@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// fakeSideband writes out the kind of lines go-git's sideband
+// demultiplexer sends to a CloneOptions/FetchOptions Progress writer.
+const fakeSideband = "Counting objects: 100% (4/4), done.\r\n" +
+	"Receiving objects: 50% (2/4), 512 bytes\r" +
+	"Receiving objects: 100% (4/4), 1.21 KiB | 620.00 KiB/s, done.\n"
+
+// recordingProgress is a Progress that just records every call it gets, for
+// asserting against in tests.
+type recordingProgress struct {
+	phases  []string
+	objects [][2]uint64
+	bytes   []uint64
+}
+
+func (r *recordingProgress) OnPhase(name string) { r.phases = append(r.phases, name) }
+func (r *recordingProgress) OnObjects(received, total uint64) {
+	r.objects = append(r.objects, [2]uint64{received, total})
+}
+func (r *recordingProgress) OnBytes(n uint64) { r.bytes = append(r.bytes, n) }
+func (r *recordingProgress) OnRef(ref string, oldHash, newHash plumbing.Hash) {}
+
+// TestNewProgressWriter tests that NewProgressWriter turns raw sideband
+// text into structured Progress calls, including OnBytes, which it must
+// derive from the size reported on "Receiving objects" lines rather than
+// only ever being driven directly by callers.
+func TestNewProgressWriter(t *testing.T) {
+	p := &recordingProgress{}
+	w := NewProgressWriter(p)
+	if _, err := w.Write([]byte(fakeSideband)); err != nil {
+		t.Fatalf("failed to write fake sideband data: %s", err)
+	}
+
+	if len(p.objects) != 2 {
+		t.Fatalf("expected 2 object updates, got %d: %v", len(p.objects), p.objects)
+	}
+	if p.objects[1] != [2]uint64{4, 4} {
+		t.Fatalf("unexpected final object update: %v", p.objects[1])
+	}
+
+	if len(p.bytes) != 2 {
+		t.Fatalf("expected 2 byte updates, got %d: %v", len(p.bytes), p.bytes)
+	}
+	if p.bytes[0] != 512 {
+		t.Fatalf("unexpected first byte update: %d", p.bytes[0])
+	}
+	secondChunkKiB := 1.21
+	wantSecond := uint64(secondChunkKiB*1024) - 512
+	if p.bytes[1] != wantSecond {
+		t.Fatalf("unexpected second byte update: %d, want %d", p.bytes[1], wantSecond)
+	}
+}
+
+// TestTextProgress tests the TextProgress implementation of Progress.
+func TestTextProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewTextProgress(&buf)
+	p.OnPhase("Receiving objects")
+	p.OnObjects(2, 4)
+	p.OnBytes(1024)
+	p.OnRef("refs/heads/master", plumbing.ZeroHash, plumbing.NewHash("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+
+	out := buf.String()
+	for _, want := range []string{
+		"Receiving objects\n",
+		"objects: 2/4 (50%)\n",
+		"bytes: 1024\n",
+		"ref refs/heads/master:",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got %q", want, out)
+		}
+	}
+}
+
+// TestPrometheusProgress tests the PrometheusProgress implementation of
+// Progress and its Prometheus text exposition output.
+func TestPrometheusProgress(t *testing.T) {
+	p := NewPrometheusProgress()
+	p.OnObjects(3, 4)
+	p.OnBytes(512)
+	p.OnBytes(512)
+	p.OnRef("refs/heads/master", plumbing.ZeroHash, plumbing.NewHash("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	p.MarkSuccess(1700000000)
+
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil {
+		t.Fatalf("failed to render metrics: %s", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"git_mirror_objects_total 3\n",
+		"git_mirror_bytes_total 1024\n",
+		"git_mirror_last_success_timestamp_seconds 1700000000\n",
+		`git_mirror_ref_updates_total{ref="refs/heads/master"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected metrics to contain %q, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TextProgress is a Progress that writes one human-readable line per event
+// to an io.Writer, for mirrors run interactively or that just want to log
+// their output.
+type TextProgress struct {
+	w io.Writer
+}
+
+// NewTextProgress returns a TextProgress writing to w.
+func NewTextProgress(w io.Writer) *TextProgress {
+	return &TextProgress{w: w}
+}
+
+// OnPhase implements Progress.
+func (t *TextProgress) OnPhase(name string) {
+	fmt.Fprintf(t.w, "%s\n", name)
+}
+
+// OnObjects implements Progress.
+func (t *TextProgress) OnObjects(received, total uint64) {
+	if total == 0 {
+		fmt.Fprintf(t.w, "objects: %d\n", received)
+		return
+	}
+	fmt.Fprintf(t.w, "objects: %d/%d (%d%%)\n", received, total, received*100/total)
+}
+
+// OnBytes implements Progress.
+func (t *TextProgress) OnBytes(n uint64) {
+	fmt.Fprintf(t.w, "bytes: %d\n", n)
+}
+
+// OnRef implements Progress.
+func (t *TextProgress) OnRef(ref string, oldHash, newHash plumbing.Hash) {
+	fmt.Fprintf(t.w, "ref %s: %s -> %s\n", ref, oldHash, newHash)
+}
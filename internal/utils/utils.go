@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: Andrei Gherzan <andrei@gherzan.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package utils provides small, independently testable helpers built on top
+// of go-git that the mirror driver composes to create repositories, inspect
+// their refs, and move objects between them.
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	formatpkg "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// SortSlice returns a sorted copy of s, leaving s itself untouched.
+func SortSlice(s []string) []string {
+	sorted := make([]string, len(s))
+	copy(sorted, s)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// SlicesAreEqual reports whether a and b contain the same strings,
+// irrespective of order.
+func SlicesAreEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	as, bs := SortSlice(a), SortSlice(b)
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewBareRepo creates a new, empty bare repository at path, using the
+// ObjectFormat selected by opts (ObjectFormatSHA1 when none is given).
+func NewBareRepo(path string, opts ...RepoOption) (*git.Repository, error) {
+	o := newRepoOptions(opts)
+	if err := o.format.checkSupported(); err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainInitWithOptions(path, &git.PlainInitOptions{
+		Bare: true,
+		InitOptions: git.InitOptions{
+			DefaultBranch: plumbing.Master,
+		},
+		ObjectFormat: formatpkg.ObjectFormat(o.format),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init bare repo at %s: %w", path, err)
+	}
+	return repo, nil
+}
+
+// NewTestRepo creates a non-bare repository at path with a single commit on
+// refs/heads/master, and a ref for every name in refs pointing at that same
+// commit. It returns the commit hash shared by all of those refs, which
+// callers use to assert against with RepoRefsCheckHash. The ObjectFormat
+// selected by opts (ObjectFormatSHA1 when none is given) controls the hash
+// width of that commit.
+func NewTestRepo(path string, refs []string, opts ...RepoOption) (*git.Repository, plumbing.Hash, error) {
+	o := newRepoOptions(opts)
+	if err := o.format.checkSupported(); err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	repo, err := git.PlainInitWithOptions(path, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{
+			DefaultBranch: plumbing.Master,
+		},
+		ObjectFormat: formatpkg.ObjectFormat(o.format),
+	})
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to init repo at %s: %w", path, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to get worktree for %s: %w", path, err)
+	}
+
+	hash, err := worktree.Commit("git-mirror-me test commit", &git.CommitOptions{
+		AllowEmptyCommits: true,
+		Author: &object.Signature{
+			Name:  "git-mirror-me",
+			Email: "git-mirror-me@localhost",
+		},
+	})
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to commit in %s: %w", path, err)
+	}
+
+	for _, ref := range refs {
+		err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(ref), hash))
+		if err != nil {
+			return nil, plumbing.ZeroHash, fmt.Errorf("failed to set ref %s in %s: %w", ref, path, err)
+		}
+	}
+
+	return repo, hash, nil
+}
+
+// RepoRefsSlice returns the name of every ref stored in repo, as strings.
+func RepoRefsSlice(repo *git.Repository) ([]string, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refs: %w", err)
+	}
+	refs := []string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref.Name().String())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate refs: %w", err)
+	}
+	return refs, nil
+}
+
+// RepoRefsCheckHash reports whether every hash reference (that is, every
+// ref other than symbolic ones such as HEAD) in repo resolves to hash.
+// hash has the width plumbing.Hash is compiled with: a SHA-1 digest in a
+// default build, a SHA-256 digest only under go-git's "sha256" build tag.
+// ParseHash builds one from a hex string of that width and rejects the
+// other width with an error.
+func RepoRefsCheckHash(repo *git.Repository, hash plumbing.Hash) (bool, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return false, fmt.Errorf("failed to get refs: %w", err)
+	}
+	ok := true
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		if ref.Hash() != hash {
+			ok = false
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to iterate refs: %w", err)
+	}
+	return ok, nil
+}
+
+// SpecsToStrings converts a slice of config.RefSpec to their string form.
+func SpecsToStrings(specs []config.RefSpec) []string {
+	out := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		out = append(out, spec.String())
+	}
+	return out
+}
+
+// RefsToStrings converts a slice of plumbing references to the string form
+// of their names.
+func RefsToStrings(refs []*plumbing.Reference) []string {
+	out := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		out = append(out, ref.Name().String())
+	}
+	return out
+}